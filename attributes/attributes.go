@@ -18,7 +18,9 @@ package attributes
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -26,6 +28,11 @@ import (
 	"net/http"
 )
 
+// maxAttributeValueSize is the approximate per-key Guest Attribute value
+// size limit. Payloads larger than this must be split across multiple
+// keys by PostAttributeChunked.
+const maxAttributeValueSize = 256 * 1024
+
 // PostAttribute posts data to Guest Attributes
 func PostAttribute(url string, value io.Reader) error {
 	req, err := http.NewRequest("PUT", url, value)
@@ -69,3 +76,149 @@ func PostAttributeCompressed(url string, body any) error {
 
 	return PostAttribute(url, buf)
 }
+
+// getAttribute reads the raw value of a Guest Attribute.
+func getAttribute(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf(`received status code %q for request "%s %s"`+"\n Error response: %s", resp.Status, req.Method, req.URL.String(), string(b))
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// attributeManifest describes how the parts written by PostAttributeChunked
+// can be reassembled and verified by GetAttributeChunked.
+type attributeManifest struct {
+	PartCount int    `json:"partCount"`
+	SHA256    string `json:"sha256"`
+	Gzip      bool   `json:"gzip"`
+}
+
+// attributeChunkWriter buffers the already gzipped and base64-encoded
+// stream written to it and flushes completed chunks to Guest Attributes as
+// soon as they reach maxAttributeValueSize, so memory use stays bounded
+// regardless of the total payload size.
+type attributeChunkWriter struct {
+	url string
+	buf bytes.Buffer
+	idx int
+}
+
+func (w *attributeChunkWriter) Write(p []byte) (int, error) {
+	n, _ := w.buf.Write(p)
+	for w.buf.Len() >= maxAttributeValueSize {
+		if err := w.flushChunk(w.buf.Next(maxAttributeValueSize)); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (w *attributeChunkWriter) flushChunk(chunk []byte) error {
+	key := fmt.Sprintf("%s/part-%03d", w.url, w.idx)
+	if err := PostAttribute(key, bytes.NewReader(chunk)); err != nil {
+		return fmt.Errorf("error posting attribute chunk %d: %v", w.idx, err)
+	}
+	w.idx++
+	return nil
+}
+
+func (w *attributeChunkWriter) flush() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	return w.flushChunk(w.buf.Next(w.buf.Len()))
+}
+
+// PostAttributeChunked gzips and base64-encodes data read from r and posts
+// it to Guest Attributes, splitting the result across as many "<url>/part-NNN"
+// keys as needed to stay under the per-key size limit, plus a "<url>/manifest"
+// key recording the part count and a sha256 of the uncompressed data. It
+// streams from r rather than buffering the whole payload, so memory use is
+// bounded by maxAttributeValueSize regardless of input size. Use
+// GetAttributeChunked to reassemble and verify the result.
+func PostAttributeChunked(url string, r io.Reader) error {
+	hasher := sha256.New()
+
+	w := &attributeChunkWriter{url: url}
+	b := base64.NewEncoder(base64.StdEncoding, w)
+	zw := gzip.NewWriter(b)
+
+	if _, err := io.Copy(zw, io.TeeReader(r, hasher)); err != nil {
+		return fmt.Errorf("error compressing attribute data: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := b.Close(); err != nil {
+		return err
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	manifest := attributeManifest{
+		PartCount: w.idx,
+		SHA256:    hex.EncodeToString(hasher.Sum(nil)),
+		Gzip:      true,
+	}
+	return PostAttributeCompressed(url+"/manifest", manifest)
+}
+
+// GetAttributeChunked reassembles and decompresses a payload written by
+// PostAttributeChunked, verifying it against the manifest's sha256.
+func GetAttributeChunked(url string) (io.Reader, error) {
+	manifestRaw, err := getAttribute(url + "/manifest")
+	if err != nil {
+		return nil, fmt.Errorf("error reading attribute manifest: %v", err)
+	}
+
+	zr, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(manifestRaw)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating gzip reader for manifest: %v", err)
+	}
+	var manifest attributeManifest
+	if err := json.NewDecoder(zr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("error parsing attribute manifest: %v", err)
+	}
+
+	var encoded bytes.Buffer
+	for i := 0; i < manifest.PartCount; i++ {
+		part, err := getAttribute(fmt.Sprintf("%s/part-%03d", url, i))
+		if err != nil {
+			return nil, fmt.Errorf("error reading attribute chunk %d: %v", i, err)
+		}
+		encoded.Write(part)
+	}
+
+	dr, err := gzip.NewReader(base64.NewDecoder(base64.StdEncoding, &encoded))
+	if err != nil {
+		return nil, fmt.Errorf("error creating gzip reader: %v", err)
+	}
+	defer dr.Close()
+
+	data, err := ioutil.ReadAll(dr)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing attribute data: %v", err)
+	}
+
+	if manifest.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); got != manifest.SHA256 {
+			return nil, fmt.Errorf("attribute data checksum mismatch: manifest has %s, got %s", manifest.SHA256, got)
+		}
+	}
+
+	return bytes.NewReader(data), nil
+}
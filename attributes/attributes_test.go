@@ -0,0 +1,192 @@
+//  Copyright 2024 Google Inc. All Rights Reserved.
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package attributes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// chunkedAttributeStore is a minimal in-memory Guest Attributes stand-in
+// for exercising PostAttributeChunked/GetAttributeChunked end to end.
+type chunkedAttributeStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newChunkedAttributeStore() *chunkedAttributeStore {
+	return &chunkedAttributeStore{values: map[string][]byte{}}
+}
+
+func (s *chunkedAttributeStore) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.values[r.URL.Path] = b
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			b, ok := s.values[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(b)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func TestPostAttributeChunkedRoundTrip(t *testing.T) {
+	store := newChunkedAttributeStore()
+	ts := httptest.NewServer(store.handler())
+	defer ts.Close()
+
+	// Base64 of random bytes (so gzip can shrink it only a little) and large
+	// enough that it has to be split across more than one
+	// maxAttributeValueSize chunk.
+	raw := make([]byte, 3*maxAttributeValueSize)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatalf("rand.Read() error = %v", err)
+	}
+	want := base64.StdEncoding.EncodeToString(raw)
+
+	if err := PostAttributeChunked(ts.URL+"/wua-inventory", strings.NewReader(want)); err != nil {
+		t.Fatalf("PostAttributeChunked() error = %v", err)
+	}
+
+	store.mu.Lock()
+	parts := 0
+	for k := range store.values {
+		if strings.Contains(k, "/part-") {
+			parts++
+		}
+	}
+	store.mu.Unlock()
+	if parts < 2 {
+		t.Fatalf("got %d parts, want PostAttributeChunked to split the payload into more than one", parts)
+	}
+
+	r, err := GetAttributeChunked(ts.URL + "/wua-inventory")
+	if err != nil {
+		t.Fatalf("GetAttributeChunked() error = %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("round trip mismatch: got %d bytes, want %d bytes", len(got), len(want))
+	}
+}
+
+func TestPostAttributeChunkedSmallPayloadSinglePart(t *testing.T) {
+	store := newChunkedAttributeStore()
+	ts := httptest.NewServer(store.handler())
+	defer ts.Close()
+
+	want := "small payload"
+	if err := PostAttributeChunked(ts.URL+"/small", strings.NewReader(want)); err != nil {
+		t.Fatalf("PostAttributeChunked() error = %v", err)
+	}
+
+	store.mu.Lock()
+	parts := 0
+	for k := range store.values {
+		if strings.Contains(k, "/part-") {
+			parts++
+		}
+	}
+	store.mu.Unlock()
+	if parts != 1 {
+		t.Errorf("got %d parts, want 1", parts)
+	}
+
+	r, err := GetAttributeChunked(ts.URL + "/small")
+	if err != nil {
+		t.Fatalf("GetAttributeChunked() error = %v", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("io.ReadAll() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestGetAttributeChunkedChecksumMismatch(t *testing.T) {
+	store := newChunkedAttributeStore()
+	ts := httptest.NewServer(store.handler())
+	defer ts.Close()
+
+	if err := PostAttribute(ts.URL+"/bad/part-000", gzipBase64(t, "actual data")); err != nil {
+		t.Fatalf("PostAttribute() error = %v", err)
+	}
+	manifest := attributeManifest{
+		PartCount: 1,
+		SHA256:    sha256Hex("different data"),
+		Gzip:      true,
+	}
+	if err := PostAttributeCompressed(ts.URL+"/bad/manifest", manifest); err != nil {
+		t.Fatalf("PostAttributeCompressed() error = %v", err)
+	}
+
+	_, err := GetAttributeChunked(ts.URL + "/bad")
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("GetAttributeChunked() error = %v, want a checksum mismatch error", err)
+	}
+}
+
+func gzipBase64(t *testing.T, data string) io.Reader {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	b := base64.NewEncoder(base64.StdEncoding, buf)
+	zw := gzip.NewWriter(b)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("zw.Write() error = %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zw.Close() error = %v", err)
+	}
+	if err := b.Close(); err != nil {
+		t.Fatalf("b.Close() error = %v", err)
+	}
+	return buf
+}
+
+func sha256Hex(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
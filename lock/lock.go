@@ -0,0 +1,39 @@
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lock provides a cross-platform single-instance lock for the
+// agent process. Acquire and TryAcquire are implemented per-OS: flock(2)
+// on Linux/Darwin/BSD and a named global mutex on Windows.
+package lock
+
+import "fmt"
+
+// Releaser releases a lock obtained from Acquire or TryAcquire. It is safe
+// to call Release more than once.
+type Releaser interface {
+	Release() error
+}
+
+// LockedError is returned when a lock is already held by another process.
+// Holder is the PID of the process holding the lock, or 0 if the OS does
+// not make that information available.
+type LockedError struct {
+	Holder int
+}
+
+func (e *LockedError) Error() string {
+	if e.Holder > 0 {
+		return fmt.Sprintf("lock already held by pid %d", e.Holder)
+	}
+	return "lock already held by another process"
+}
@@ -0,0 +1,124 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const lockDir = "/run/lock"
+
+// flockPollInterval is how often TryAcquire retries a non-blocking flock
+// while waiting for a timeout to elapse. Polling (rather than a blocking
+// LOCK_EX in a goroutine) keeps the wait cancelable: a blocked LOCK_EX
+// syscall can't be interrupted, so closing the fd out from under it on
+// timeout would be undefined behavior and leak the goroutine forever.
+const flockPollInterval = 50 * time.Millisecond
+
+type fileLock struct {
+	f    *os.File
+	path string
+	once sync.Once
+	err  error
+}
+
+// Release releases the lock, closes the lock file and removes it. It is
+// safe to call more than once; only the first call touches the file.
+func (l *fileLock) Release() error {
+	l.once.Do(func() {
+		syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+
+		// Only unlink the path if it still refers to the inode we locked:
+		// a naive unconditional os.Remove races a second acquirer that is
+		// blocked in flock() on this same (now-unlinked) inode while a
+		// third process opens a brand-new file at path and gets an
+		// independent, concurrently-held flock.
+		if fi, statErr := l.f.Stat(); statErr == nil {
+			if pathFi, err := os.Stat(l.path); err == nil && os.SameFile(fi, pathFi) {
+				os.Remove(l.path)
+			}
+		}
+
+		l.err = l.f.Close()
+	})
+	return l.err
+}
+
+// Acquire blocks until the named lock is obtained.
+func Acquire(name string) (Releaser, error) {
+	return TryAcquire(name, 0)
+}
+
+// TryAcquire attempts to obtain the named lock, waiting up to timeout for
+// it to become available. A timeout of 0 blocks indefinitely.
+func TryAcquire(name string, timeout time.Duration) (Releaser, error) {
+	if err := os.MkdirAll(lockDir, 1777); err != nil && !os.IsExist(err) {
+		return nil, fmt.Errorf("lock: cannot create lock directory %q: %v", lockDir, err)
+	}
+
+	path := filepath.Join(lockDir, name+".lock")
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("lock: cannot open lock file %q: %v", path, err)
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if err != syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, fmt.Errorf("lock: flock failed on %q: %v", path, err)
+		}
+		if timeout > 0 && time.Now().After(deadline) {
+			f.Close()
+			return nil, &LockedError{Holder: lockHolderPID(path)}
+		}
+		time.Sleep(flockPollInterval)
+	}
+
+	f.Truncate(0)
+	f.WriteString(strconv.Itoa(os.Getpid()))
+
+	return &fileLock{f: f, path: path}, nil
+}
+
+// lockHolderPID best-effort reads the PID written into the lock file by
+// the process currently holding it.
+func lockHolderPID(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,141 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func lockName(t *testing.T) string {
+	t.Helper()
+	return "lock_unix_test_" + t.Name()
+}
+
+func TestTryAcquireThenReleaseAllowsReacquire(t *testing.T) {
+	name := lockName(t)
+
+	l, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	l2, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("second TryAcquire() after Release() error = %v", err)
+	}
+	if err := l2.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+}
+
+func TestReleaseIsIdempotent(t *testing.T) {
+	l, err := TryAcquire(lockName(t), time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("first Release() error = %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Errorf("second Release() error = %v, want nil", err)
+	}
+}
+
+func TestTryAcquireReturnsLockedErrorWithHolderPID(t *testing.T) {
+	name := lockName(t)
+
+	l, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	defer l.Release()
+
+	_, err = TryAcquire(name, 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("TryAcquire() on an already-held lock: error = nil, want LockedError")
+	}
+	lerr, ok := err.(*LockedError)
+	if !ok {
+		t.Fatalf("TryAcquire() error type = %T, want *LockedError", err)
+	}
+	if lerr.Holder != os.Getpid() {
+		t.Errorf("LockedError.Holder = %d, want %d (this process's pid)", lerr.Holder, os.Getpid())
+	}
+}
+
+func TestTryAcquireSucceedsOnceHolderReleases(t *testing.T) {
+	name := lockName(t)
+
+	l, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(2 * flockPollInterval)
+		l.Release()
+		close(done)
+	}()
+
+	l2, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() while waiting for release: error = %v", err)
+	}
+	defer l2.Release()
+	<-done
+}
+
+func TestFileLockReleaseDoesNotRemoveFileALaterAcquirerIsUsing(t *testing.T) {
+	name := lockName(t)
+
+	l, err := TryAcquire(name, time.Second)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+
+	path := l.(*fileLock).path
+	// Simulate a concurrent acquirer racing Release(): a brand-new file
+	// gets created at path (as if a second process had unlinked the old
+	// one and a third process opened a fresh one) right before Release()
+	// runs. Release() must not remove it, because it no longer refers to
+	// the inode this lock actually holds.
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("os.Remove(%q) error = %v", path, err)
+	}
+	if err := os.WriteFile(path, []byte("other holder"), 0644); err != nil {
+		t.Fatalf("os.WriteFile(%q) error = %v", path, err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("lock file was removed by an unrelated Release(): os.ReadFile(%q) error = %v", path, err)
+	}
+	if string(b) != "other holder" {
+		t.Errorf("lock file contents = %q, want %q", b, "other holder")
+	}
+	os.Remove(path)
+}
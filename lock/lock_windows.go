@@ -0,0 +1,93 @@
+//go:build windows
+
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// globalPrefix puts the mutex in the Global\ namespace so the lock is
+// enforced across all sessions, not just the one the agent happens to be
+// running in.
+const globalPrefix = `Global\`
+
+type mutexLock struct {
+	handle   windows.Handle
+	once     sync.Once
+	released error
+}
+
+// Release releases and closes the mutex handle. It is safe to call more
+// than once; only the first call touches the handle.
+func (l *mutexLock) Release() error {
+	l.once.Do(func() {
+		if err := windows.ReleaseMutex(l.handle); err != nil {
+			windows.CloseHandle(l.handle)
+			l.released = fmt.Errorf("lock: ReleaseMutex failed: %v", err)
+			return
+		}
+		l.released = windows.CloseHandle(l.handle)
+	})
+	return l.released
+}
+
+// Acquire blocks until the named lock is obtained.
+func Acquire(name string) (Releaser, error) {
+	return TryAcquire(name, 0)
+}
+
+// TryAcquire attempts to obtain the named lock, waiting up to timeout for
+// it to become available. A timeout of 0 blocks indefinitely.
+func TryAcquire(name string, timeout time.Duration) (Releaser, error) {
+	namePtr, err := windows.UTF16PtrFromString(globalPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("lock: invalid lock name %q: %v", name, err)
+	}
+
+	// initialOwner=true grants this call ownership immediately when it is
+	// the one creating the mutex; when the mutex already exists,
+	// CreateMutex never grants ownership regardless of initialOwner, so we
+	// still have to wait for it below.
+	handle, err := windows.CreateMutex(nil, true, namePtr)
+	if handle == 0 {
+		return nil, fmt.Errorf("lock: CreateMutexW failed: %v", err)
+	}
+
+	if err == windows.ERROR_ALREADY_EXISTS {
+		waitMillis := uint32(windows.INFINITE)
+		if timeout > 0 {
+			waitMillis = uint32(timeout / time.Millisecond)
+		}
+
+		switch event, err := windows.WaitForSingleObject(handle, waitMillis); event {
+		case windows.WAIT_OBJECT_0, windows.WAIT_ABANDONED:
+			// Lock acquired, possibly from a process that exited without
+			// releasing it cleanly.
+		case uint32(windows.WAIT_TIMEOUT):
+			windows.CloseHandle(handle)
+			return nil, &LockedError{}
+		default:
+			windows.CloseHandle(handle)
+			return nil, fmt.Errorf("lock: WaitForSingleObject failed: %v", err)
+		}
+	}
+
+	return &mutexLock{handle: handle}, nil
+}
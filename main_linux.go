@@ -17,12 +17,10 @@ package main
 import (
 	"context"
 	"errors"
-	"os"
-	"path/filepath"
-	"syscall"
 	"time"
 
 	"github.com/GoogleCloudPlatform/guest-logging-go/logger"
+	"github.com/GoogleCloudPlatform/osconfig/lock"
 )
 
 func runService(ctx context.Context) {
@@ -30,32 +28,12 @@ func runService(ctx context.Context) {
 }
 
 func obtainLock() {
-	lockFile := "/run/lock/osconfig_agent.lock"
-
-	err := os.Mkdir(filepath.Dir(lockFile), 1777)
-	if err != nil && !os.IsExist(err) {
-		logger.Fatalf("Cannot obtain agent lock: %v", err)
-	}
-
-	f, err := os.OpenFile(lockFile, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil && !os.IsExist(err) {
-		logger.Fatalf("Cannot obtain agent lock: %v", err)
-	}
-
-	c := make(chan error)
-	go func() {
-		c <- syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
-	}()
-	select {
-	case err := <-c:
-		if err != nil {
-			logger.Fatalf("Cannot obtain agent lock, is the agent already running? Error: %v", err)
-		}
-	case <-time.After(time.Second):
-		logger.Fatalf("OSConfig agent lock already held, is the agent already running?")
+	l, err := lock.TryAcquire("osconfig_agent", time.Second)
+	if err != nil {
+		logger.Fatalf("Cannot obtain agent lock, is the agent already running? Error: %v", err)
 	}
 
-	deferredFuncs = append(deferredFuncs, func() { syscall.Flock(int(f.Fd()), syscall.LOCK_UN); f.Close(); os.Remove(lockFile) })
+	deferredFuncs = append(deferredFuncs, func() { l.Release() })
 }
 
 func wuaUpdates(ctx context.Context, _ string) error {
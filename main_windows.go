@@ -0,0 +1,92 @@
+//  Copyright 2019 Google Inc. All Rights Reserved.
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/GoogleCloudPlatform/guest-logging-go/logger"
+	"github.com/GoogleCloudPlatform/osconfig/lock"
+	"github.com/GoogleCloudPlatform/osconfig/packages"
+	"golang.org/x/sys/windows/svc"
+)
+
+const serviceName = "google_osconfig_agent"
+
+func obtainLock() {
+	l, err := lock.TryAcquire("osconfig_agent", time.Second)
+	if err != nil {
+		logger.Fatalf("Cannot obtain agent lock, is the agent already running? Error: %v", err)
+	}
+
+	deferredFuncs = append(deferredFuncs, func() { l.Release() })
+}
+
+type service struct {
+	ctx context.Context
+	run func(context.Context)
+}
+
+func (s *service) Execute(_ []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	ctx, cncl := context.WithCancel(s.ctx)
+	defer cncl()
+	done := make(chan struct{})
+
+	go func() {
+		s.run(ctx)
+		close(done)
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case <-done:
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				status <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				cncl()
+			default:
+			}
+		}
+	}
+}
+
+func runService(ctx context.Context) {
+	if err := svc.Run(serviceName, &service{run: run, ctx: ctx}); err != nil {
+		logger.Fatalf("svc.Run error: %v", err)
+	}
+}
+
+func wuaUpdates(ctx context.Context, query string) error {
+	updts, err := packages.WUAUpdates(ctx, query)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(updts)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stdout, string(data))
+	return nil
+}
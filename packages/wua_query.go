@@ -0,0 +1,174 @@
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ServerSelection selects which source IUpdateSearcher.ServerSelection
+// queries against.
+type ServerSelection int32
+
+const (
+	// ServerSelectionDefault uses the default server for the update source.
+	ServerSelectionDefault ServerSelection = iota
+	// ServerSelectionManagedServer forces the search against a WSUS server.
+	ServerSelectionManagedServer
+	// ServerSelectionWindowsUpdate forces the search against the public
+	// Windows Update service.
+	ServerSelectionWindowsUpdate
+	// ServerSelectionOthers forces the search against another source, such
+	// as an offline .cab scan file.
+	ServerSelectionOthers
+)
+
+// SearchScope selects whether WUAQuery searches for software updates,
+// driver updates, or both.
+type SearchScope int
+
+const (
+	// SearchScopeSoftwareAndDriver searches both software and driver updates.
+	SearchScopeSoftwareAndDriver SearchScope = iota
+	// SearchScopeSoftwareOnly restricts the search to software updates.
+	SearchScopeSoftwareOnly
+	// SearchScopeDriverOnly restricts the search to driver updates.
+	SearchScopeDriverOnly
+)
+
+// WUAQuery is a typed builder for Windows Update Agent search filter
+// strings, used in place of hand-assembled WQL-like query strings passed
+// to WUAUpdates and GetWUAUpdateCollection. The builder itself has no COM
+// dependency; only the methods that actually run a search (in
+// wua_query_windows.go) are Windows-only.
+type WUAQuery struct {
+	clauses         []string
+	serverSelection ServerSelection
+	searchScope     SearchScope
+}
+
+// NewWUAQuery returns an empty WUAQuery.
+func NewWUAQuery() *WUAQuery {
+	return &WUAQuery{}
+}
+
+// IsInstalled filters on the IsInstalled criteria.
+func (q *WUAQuery) IsInstalled(installed bool) *WUAQuery {
+	q.clauses = append(q.clauses, fmt.Sprintf("IsInstalled=%d", boolToInt(installed)))
+	return q
+}
+
+// IsHidden filters on the IsHidden criteria.
+func (q *WUAQuery) IsHidden(hidden bool) *WUAQuery {
+	q.clauses = append(q.clauses, fmt.Sprintf("IsHidden=%d", boolToInt(hidden)))
+	return q
+}
+
+// Type filters on the update Type, for example "Software" or "Driver".
+func (q *WUAQuery) Type(t string) *WUAQuery {
+	q.clauses = append(q.clauses, "Type="+wqlQuote(t))
+	return q
+}
+
+// CategoryIDs filters on one or more CategoryIDs, ORed together.
+func (q *WUAQuery) CategoryIDs(ids ...string) *WUAQuery {
+	if len(ids) == 0 {
+		return q
+	}
+	cs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cs = append(cs, "CategoryIDs contains "+wqlQuote(id))
+	}
+	q.clauses = append(q.clauses, "("+strings.Join(cs, " or ")+")")
+	return q
+}
+
+// Severity filters on the update Severity, for example "Critical" or
+// "Important".
+func (q *WUAQuery) Severity(s string) *WUAQuery {
+	q.clauses = append(q.clauses, "Severity="+wqlQuote(s))
+	return q
+}
+
+// AutoSelectOnWebSites filters on the AutoSelectOnWebSites criteria.
+func (q *WUAQuery) AutoSelectOnWebSites(auto bool) *WUAQuery {
+	q.clauses = append(q.clauses, fmt.Sprintf("AutoSelectOnWebSites=%d", boolToInt(auto)))
+	return q
+}
+
+// And adds a raw WQL-like clause, ANDed with the rest of the query. It is
+// an escape hatch for criteria not covered by the typed helpers above.
+func (q *WUAQuery) And(clause string) *WUAQuery {
+	q.clauses = append(q.clauses, clause)
+	return q
+}
+
+// Or ORs together the compiled form of the provided queries and adds the
+// result as a single clause.
+func (q *WUAQuery) Or(queries ...*WUAQuery) *WUAQuery {
+	cs := make([]string, 0, len(queries))
+	for _, sub := range queries {
+		if s := sub.String(); s != "" {
+			cs = append(cs, s)
+		}
+	}
+	if len(cs) == 0 {
+		return q
+	}
+	q.clauses = append(q.clauses, "("+strings.Join(cs, " or ")+")")
+	return q
+}
+
+// WithServerSelection sets the IUpdateSearcher.ServerSelection to use,
+// letting operators on WSUS or offline .cab scan files point the searcher
+// at the right source.
+func (q *WUAQuery) WithServerSelection(s ServerSelection) *WUAQuery {
+	q.serverSelection = s
+	return q
+}
+
+// WithSearchScope restricts the query to software updates, driver updates,
+// or both.
+func (q *WUAQuery) WithSearchScope(s SearchScope) *WUAQuery {
+	q.searchScope = s
+	return q
+}
+
+// String compiles the query into the WUA search string accepted by
+// IUpdateSearcher.Search.
+func (q *WUAQuery) String() string {
+	clauses := append([]string(nil), q.clauses...)
+	switch q.searchScope {
+	case SearchScopeSoftwareOnly:
+		clauses = append(clauses, `Type='Software'`)
+	case SearchScopeDriverOnly:
+		clauses = append(clauses, `Type='Driver'`)
+	}
+	return strings.Join(clauses, " and ")
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// wqlQuote quotes s as a WUA search string literal, doubling any embedded
+// single quotes so callers cannot break out of the literal and inject
+// additional search criteria.
+func wqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
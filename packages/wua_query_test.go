@@ -0,0 +1,120 @@
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import "testing"
+
+func TestWUAQueryString(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *WUAQuery
+		want  string
+	}{
+		{
+			name:  "empty",
+			query: NewWUAQuery(),
+			want:  "",
+		},
+		{
+			name:  "is installed and not hidden",
+			query: NewWUAQuery().IsInstalled(false).IsHidden(false),
+			want:  "IsInstalled=0 and IsHidden=0",
+		},
+		{
+			name:  "type",
+			query: NewWUAQuery().Type("Software"),
+			want:  "Type='Software'",
+		},
+		{
+			name:  "category ids",
+			query: NewWUAQuery().CategoryIDs("cat1", "cat2"),
+			want:  "(CategoryIDs contains 'cat1' or CategoryIDs contains 'cat2')",
+		},
+		{
+			name:  "category ids empty is a no-op",
+			query: NewWUAQuery().IsInstalled(true).CategoryIDs(),
+			want:  "IsInstalled=1",
+		},
+		{
+			name:  "severity",
+			query: NewWUAQuery().Severity("Critical"),
+			want:  "Severity='Critical'",
+		},
+		{
+			name:  "auto select on web sites",
+			query: NewWUAQuery().AutoSelectOnWebSites(true),
+			want:  "AutoSelectOnWebSites=1",
+		},
+		{
+			name:  "and escape hatch",
+			query: NewWUAQuery().IsInstalled(false).And("BrowseOnly=0"),
+			want:  "IsInstalled=0 and BrowseOnly=0",
+		},
+		{
+			name:  "or",
+			query: NewWUAQuery().Or(NewWUAQuery().Type("Software"), NewWUAQuery().Type("Driver")),
+			want:  "(Type='Software' or Type='Driver')",
+		},
+		{
+			name:  "search scope software only",
+			query: NewWUAQuery().IsInstalled(false).WithSearchScope(SearchScopeSoftwareOnly),
+			want:  "IsInstalled=0 and Type='Software'",
+		},
+		{
+			name:  "search scope driver only",
+			query: NewWUAQuery().WithSearchScope(SearchScopeDriverOnly),
+			want:  "Type='Driver'",
+		},
+		{
+			name:  "embedded quote is escaped, not injected",
+			query: NewWUAQuery().Type(`Software' or IsInstalled=0 --`),
+			want:  `Type='Software'' or IsInstalled=0 --'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.query.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWUAQueryStringRepeatedCallsDoNotAccumulateSearchScope(t *testing.T) {
+	q := NewWUAQuery().WithSearchScope(SearchScopeSoftwareOnly)
+	first := q.String()
+	second := q.String()
+	if first != second {
+		t.Errorf("String() not idempotent: first call %q, second call %q", first, second)
+	}
+}
+
+func TestWqlQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Software", want: "'Software'"},
+		{in: "", want: "''"},
+		{in: "O'Brien", want: "'O''Brien'"},
+		{in: `' or 1=1 --`, want: `''' or 1=1 --'`},
+	}
+
+	for _, tt := range tests {
+		if got := wqlQuote(tt.in); got != tt.want {
+			t.Errorf("wqlQuote(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
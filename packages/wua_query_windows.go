@@ -0,0 +1,54 @@
+/*
+Copyright 2024 Google Inc. All Rights Reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package packages
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetWUAUpdateCollectionForQuery queries the Windows Update Agent API
+// searcher with the provided WUAQuery and returns a IUpdateCollection.
+func (s *IUpdateSession) GetWUAUpdateCollectionForQuery(ctx context.Context, q *WUAQuery) (*IUpdateCollection, error) {
+	searcherRaw, err := s.CallMethod("CreateUpdateSearcher")
+	if err != nil {
+		return nil, fmt.Errorf("error calling CreateUpdateSearcher: %v"+GetScodeString(ctx, err), err)
+	}
+	searcher := searcherRaw.ToIDispatch()
+	defer searcher.Release()
+
+	if _, err := searcher.PutProperty("ServerSelection", int32(q.serverSelection)); err != nil {
+		return nil, fmt.Errorf(`searcher.PutProperty("ServerSelection"): %v`, err)
+	}
+
+	return collectionFromSearcher(ctx, searcher, q.String())
+}
+
+// WUAUpdatesForQuery queries the Windows Update Agent API searcher with the
+// provided WUAQuery.
+func WUAUpdatesForQuery(ctx context.Context, q *WUAQuery) ([]WUAPackage, error) {
+	session, err := NewUpdateSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating NewUpdateSession: %v", err)
+	}
+	defer session.Close()
+
+	updts, err := session.GetWUAUpdateCollectionForQuery(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("error calling GetWUAUpdateCollectionForQuery with query %q: %v", q.String(), err)
+	}
+	defer updts.Release()
+
+	return packagesFromCollection(updts)
+}
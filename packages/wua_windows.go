@@ -16,7 +16,9 @@ package packages
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/GoogleCloudPlatform/osconfig/clog"
 	ole "github.com/go-ole/go-ole"
@@ -363,6 +365,11 @@ func WUAUpdates(ctx context.Context, query string) ([]WUAPackage, error) {
 	}
 	defer updts.Release()
 
+	return packagesFromCollection(updts)
+}
+
+// packagesFromCollection extracts a WUAPackage for every update in updts.
+func packagesFromCollection(updts *IUpdateCollection) ([]WUAPackage, error) {
 	updtCnt, err := updts.Count()
 	if err != nil {
 		return nil, err
@@ -404,7 +411,8 @@ func (s *IUpdateSession) DownloadWUAUpdateCollection(ctx context.Context, update
 	return nil
 }
 
-// InstallWUAUpdateCollection installs all updates in a IUpdateCollection
+// InstallWUAUpdateCollection installs all updates in a IUpdateCollection.
+// For progress reporting and cancellation, use InstallWUAUpdateCollectionAsync instead.
 func (s *IUpdateSession) InstallWUAUpdateCollection(ctx context.Context, updates *IUpdateCollection) error {
 	// returns IUpdateInstallersession *ole.IDispatch,
 	// https://docs.microsoft.com/en-us/windows/desktop/api/wuapi/nf-wuapi-iupdatesession-createupdateinstaller
@@ -419,13 +427,430 @@ func (s *IUpdateSession) InstallWUAUpdateCollection(ctx context.Context, updates
 		return fmt.Errorf("error calling PutProperty Updates on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
 	}
 
-	// TODO: Look into using the async methods and attempt to track/log progress.
 	if _, err := installer.CallMethod("Install"); err != nil {
 		return fmt.Errorf("error calling method Install on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
 	}
 	return nil
 }
 
+// WUADownloadProgress reports the progress of an in-flight
+// DownloadWUAUpdateCollectionAsync call.
+type WUADownloadProgress struct {
+	PercentComplete    int32
+	CurrentUpdateIndex int32
+	CurrentUpdateTitle string
+}
+
+// WUAInstallProgress reports the progress of an in-flight
+// InstallWUAUpdateCollectionAsync call.
+type WUAInstallProgress struct {
+	PercentComplete    int32
+	CurrentUpdateIndex int32
+	CurrentUpdateTitle string
+}
+
+// WUADownloadResult describes the outcome of downloading a single update
+// from an IUpdateCollection.
+type WUADownloadResult struct {
+	UpdateID   string
+	Title      string
+	HResult    int32
+	ResultCode int32
+}
+
+// WUAInstallResult describes the outcome of installing or uninstalling a
+// single update from an IUpdateCollection.
+type WUAInstallResult struct {
+	UpdateID       string
+	Title          string
+	KBArticleIDs   []string
+	HResult        int32
+	ResultCode     int32
+	RebootRequired bool
+}
+
+// wuaPollInterval is how often DownloadWUAUpdateCollectionAsync and
+// InstallWUAUpdateCollectionAsync poll the WUA job for progress. WUA reports
+// progress through IDownloadProgressChangedCallback/
+// IInstallationProgressChangedCallback COM sinks, which go-ole has no way to
+// implement; polling the job's Progress property gets us the same
+// information without one.
+const wuaPollInterval = 500 * time.Millisecond
+
+// waitForWUAJob polls a WUA download or install job (IDownloadJob or
+// IInstallationJob) until it completes, calling onProgress with the job's
+// Progress property on every poll interval. If ctx is done before the job
+// completes, the job is asked to abort and ctx.Err() is returned.
+func waitForWUAJob(ctx context.Context, job *ole.IDispatch, onProgress func(prog *ole.IDispatch)) error {
+	ticker := time.NewTicker(wuaPollInterval)
+	defer ticker.Stop()
+
+	for {
+		completed, err := propBool(job, "IsCompleted")
+		if err != nil {
+			return fmt.Errorf(`job.GetProperty("IsCompleted"): %v`, err)
+		}
+		if completed {
+			return nil
+		}
+
+		if onProgress != nil {
+			if progRaw, err := job.GetProperty("Progress"); err == nil {
+				prog := progRaw.ToIDispatch()
+				onProgress(prog)
+				prog.Release()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if _, err := job.CallMethod("RequestAbort"); err != nil {
+				clog.Errorf(ctx, "error requesting WUA job abort: %v", err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// propInt32 returns the named int32 property of dis, or 0 if it cannot be
+// read.
+func propInt32(dis *ole.IDispatch, name string) int32 {
+	v, err := dis.GetProperty(name)
+	if err != nil {
+		return 0
+	}
+	return int32(v.Val)
+}
+
+// propBool returns the named bool property of dis, erroring out rather than
+// panicking if the property can't be read or the underlying VARIANT isn't a
+// bool.
+func propBool(dis *ole.IDispatch, name string) (bool, error) {
+	v, err := dis.GetProperty(name)
+	if err != nil {
+		return false, fmt.Errorf("dis.GetProperty(%q): %v", name, err)
+	}
+	b, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("dis.GetProperty(%q): unexpected VARIANT type %T", name, v.Value())
+	}
+	return b, nil
+}
+
+// updateTitle returns the Title of the update at index in updates, or "" if
+// it cannot be read.
+func updateTitle(updates *IUpdateCollection, index int32) string {
+	updt, err := updates.Item(int(index))
+	if err != nil {
+		return ""
+	}
+	defer updt.Release()
+
+	title, err := updt.GetProperty("Title")
+	if err != nil {
+		return ""
+	}
+	return title.ToString()
+}
+
+// updateIdentity returns the UpdateID and Title of updt.
+func updateIdentity(updt *IUpdate) (updateID, title string, err error) {
+	titleRaw, err := updt.GetProperty("Title")
+	if err != nil {
+		return "", "", fmt.Errorf(`updt.GetProperty("Title"): %v`, err)
+	}
+
+	identityRaw, err := updt.GetProperty("Identity")
+	if err != nil {
+		return "", "", fmt.Errorf(`updt.GetProperty("Identity"): %v`, err)
+	}
+	identity := identityRaw.ToIDispatch()
+	defer identity.Release()
+
+	updateIDRaw, err := identity.GetProperty("UpdateID")
+	if err != nil {
+		return "", "", fmt.Errorf(`identity.GetProperty("UpdateID"): %v`, err)
+	}
+
+	return updateIDRaw.ToString(), titleRaw.ToString(), nil
+}
+
+// DownloadWUAUpdateCollectionAsync downloads all updates in updates
+// asynchronously, invoking progress (if non-nil) on every poll interval. If
+// ctx is done before the download completes, the WUA job is asked to abort
+// and ctx.Err() is returned.
+func (s *IUpdateSession) DownloadWUAUpdateCollectionAsync(ctx context.Context, updates *IUpdateCollection, progress func(WUADownloadProgress)) ([]WUADownloadResult, error) {
+	downloaderRaw, err := s.CallMethod("CreateUpdateDownloader")
+	if err != nil {
+		return nil, fmt.Errorf("error calling method CreateUpdateDownloader on IUpdateSession: %v"+GetScodeString(ctx, err), err)
+	}
+	downloader := downloaderRaw.ToIDispatch()
+	defer downloader.Release()
+
+	if _, err := downloader.PutProperty("Updates", updates.IDispatch); err != nil {
+		return nil, fmt.Errorf("error calling PutProperty Updates on IUpdateDownloader: %v"+GetScodeString(ctx, err), err)
+	}
+
+	jobRaw, err := downloader.CallMethod("BeginDownload", nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error calling method BeginDownload on IUpdateDownloader: %v"+GetScodeString(ctx, err), err)
+	}
+	job := jobRaw.ToIDispatch()
+	defer job.Release()
+
+	if err := waitForWUAJob(ctx, job, func(prog *ole.IDispatch) {
+		if progress == nil {
+			return
+		}
+		idx := propInt32(prog, "CurrentUpdateIndex")
+		progress(WUADownloadProgress{
+			PercentComplete:    propInt32(prog, "PercentComplete"),
+			CurrentUpdateIndex: idx,
+			CurrentUpdateTitle: updateTitle(updates, idx),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	resultRaw, err := job.CallMethod("GetResults")
+	if err != nil {
+		return nil, fmt.Errorf(`job.CallMethod("GetResults"): %v`+GetScodeString(ctx, err), err)
+	}
+	result := resultRaw.ToIDispatch()
+	defer result.Release()
+
+	cnt, err := updates.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []WUADownloadResult
+	for i := 0; i < int(cnt); i++ {
+		updt, err := updates.Item(i)
+		if err != nil {
+			return nil, err
+		}
+		updateID, title, err := updateIdentity(updt)
+		updt.Release()
+		if err != nil {
+			return nil, err
+		}
+
+		updtResultRaw, err := result.CallMethod("GetUpdateResult", i)
+		if err != nil {
+			return nil, fmt.Errorf(`result.CallMethod("GetUpdateResult", %d): %v`, i, err)
+		}
+		updtResult := updtResultRaw.ToIDispatch()
+		results = append(results, WUADownloadResult{
+			UpdateID:   updateID,
+			Title:      title,
+			HResult:    propInt32(updtResult, "HResult"),
+			ResultCode: propInt32(updtResult, "ResultCode"),
+		})
+		updtResult.Release()
+	}
+	return results, nil
+}
+
+// InstallWUAUpdateCollectionAsync installs all updates in updates
+// asynchronously, invoking progress (if non-nil) on every poll interval. If
+// ctx is done before the install completes, the WUA job is asked to abort
+// and ctx.Err() is returned.
+func (s *IUpdateSession) InstallWUAUpdateCollectionAsync(ctx context.Context, updates *IUpdateCollection, progress func(WUAInstallProgress)) ([]WUAInstallResult, error) {
+	installerRaw, err := s.CallMethod("CreateUpdateInstaller")
+	if err != nil {
+		return nil, fmt.Errorf("error calling method CreateUpdateInstaller on IUpdateSession: %v"+GetScodeString(ctx, err), err)
+	}
+	installer := installerRaw.ToIDispatch()
+	defer installer.Release()
+
+	if _, err := installer.PutProperty("Updates", updates.IDispatch); err != nil {
+		return nil, fmt.Errorf("error calling PutProperty Updates on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
+	}
+
+	jobRaw, err := installer.CallMethod("BeginInstall", nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error calling method BeginInstall on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
+	}
+	job := jobRaw.ToIDispatch()
+	defer job.Release()
+
+	if err := waitForWUAJob(ctx, job, func(prog *ole.IDispatch) {
+		if progress == nil {
+			return
+		}
+		idx := propInt32(prog, "CurrentUpdateIndex")
+		progress(WUAInstallProgress{
+			PercentComplete:    propInt32(prog, "PercentComplete"),
+			CurrentUpdateIndex: idx,
+			CurrentUpdateTitle: updateTitle(updates, idx),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	resultRaw, err := job.CallMethod("GetResults")
+	if err != nil {
+		return nil, fmt.Errorf(`job.CallMethod("GetResults"): %v`+GetScodeString(ctx, err), err)
+	}
+	result := resultRaw.ToIDispatch()
+	defer result.Release()
+
+	return wuaInstallResultsFromJob(updates, result)
+}
+
+// UninstallWUAUpdateCollectionAsync uninstalls all updates in updates
+// asynchronously, invoking progress (if non-nil) on every poll interval. If
+// ctx is done before the uninstall completes, the WUA job is asked to abort
+// and ctx.Err() is returned.
+func (s *IUpdateSession) UninstallWUAUpdateCollectionAsync(ctx context.Context, updates *IUpdateCollection, progress func(WUAInstallProgress)) ([]WUAInstallResult, error) {
+	installerRaw, err := s.CallMethod("CreateUpdateInstaller")
+	if err != nil {
+		return nil, fmt.Errorf("error calling method CreateUpdateInstaller on IUpdateSession: %v"+GetScodeString(ctx, err), err)
+	}
+	installer := installerRaw.ToIDispatch()
+	defer installer.Release()
+
+	if _, err := installer.PutProperty("Updates", updates.IDispatch); err != nil {
+		return nil, fmt.Errorf("error calling PutProperty Updates on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
+	}
+
+	jobRaw, err := installer.CallMethod("BeginUninstall", nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error calling method BeginUninstall on IUpdateInstaller: %v"+GetScodeString(ctx, err), err)
+	}
+	job := jobRaw.ToIDispatch()
+	defer job.Release()
+
+	if err := waitForWUAJob(ctx, job, func(prog *ole.IDispatch) {
+		if progress == nil {
+			return
+		}
+		idx := propInt32(prog, "CurrentUpdateIndex")
+		progress(WUAInstallProgress{
+			PercentComplete:    propInt32(prog, "PercentComplete"),
+			CurrentUpdateIndex: idx,
+			CurrentUpdateTitle: updateTitle(updates, idx),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	resultRaw, err := job.CallMethod("GetResults")
+	if err != nil {
+		return nil, fmt.Errorf(`job.CallMethod("GetResults"): %v`+GetScodeString(ctx, err), err)
+	}
+	result := resultRaw.ToIDispatch()
+	defer result.Release()
+
+	return wuaInstallResultsFromJob(updates, result)
+}
+
+// wuaInstallResultsFromJob builds a WUAInstallResult for each update in
+// updates from an IInstallationResult returned by an install or uninstall
+// job.
+func wuaInstallResultsFromJob(updates *IUpdateCollection, result *ole.IDispatch) ([]WUAInstallResult, error) {
+	cnt, err := updates.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []WUAInstallResult
+	for i := 0; i < int(cnt); i++ {
+		updt, err := updates.Item(i)
+		if err != nil {
+			return nil, err
+		}
+		updateID, title, err := updateIdentity(updt)
+		if err != nil {
+			updt.Release()
+			return nil, err
+		}
+		kbArticleIDs, err := updt.kbaIDs()
+		updt.Release()
+		if err != nil {
+			return nil, err
+		}
+
+		updtResultRaw, err := result.CallMethod("GetUpdateResult", i)
+		if err != nil {
+			return nil, fmt.Errorf(`result.CallMethod("GetUpdateResult", %d): %v`, i, err)
+		}
+		updtResult := updtResultRaw.ToIDispatch()
+
+		rebootRequired, err := propBool(updtResult, "RebootRequired")
+		if err != nil {
+			updtResult.Release()
+			return nil, fmt.Errorf(`updtResult.GetProperty("RebootRequired"): %v`, err)
+		}
+		hresult := propInt32(updtResult, "HResult")
+		resultCode := propInt32(updtResult, "ResultCode")
+		updtResult.Release()
+
+		results = append(results, WUAInstallResult{
+			UpdateID:       updateID,
+			Title:          title,
+			KBArticleIDs:   kbArticleIDs,
+			HResult:        hresult,
+			ResultCode:     resultCode,
+			RebootRequired: rebootRequired,
+		})
+	}
+	return results, nil
+}
+
+// updateIDQuery builds a WUA search string matching any of updateIDs, the
+// same UpdateID='...' pattern used by RMM-style patch agents to act on a
+// curated set of updates rather than an opaque bulk query.
+func updateIDQuery(updateIDs []string) string {
+	cs := make([]string, 0, len(updateIDs))
+	for _, id := range updateIDs {
+		cs = append(cs, "UpdateID="+wqlQuote(id))
+	}
+	return strings.Join(cs, " or ")
+}
+
+// InstallWUAUpdates downloads and installs only the updates identified by
+// updateIDs, so callers can act on a curated set of KBs rather than an
+// opaque bulk query.
+func InstallWUAUpdates(ctx context.Context, updateIDs []string) ([]WUAInstallResult, error) {
+	session, err := NewUpdateSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating NewUpdateSession: %v", err)
+	}
+	defer session.Close()
+
+	updts, err := session.GetWUAUpdateCollection(ctx, updateIDQuery(updateIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error calling GetWUAUpdateCollection: %v", err)
+	}
+	defer updts.Release()
+
+	if err := session.DownloadWUAUpdateCollection(ctx, updts); err != nil {
+		return nil, fmt.Errorf("DownloadWUAUpdateCollection error: %v", err)
+	}
+
+	return session.InstallWUAUpdateCollectionAsync(ctx, updts, nil)
+}
+
+// UninstallWUAUpdates uninstalls only the updates identified by updateIDs.
+func UninstallWUAUpdates(ctx context.Context, updateIDs []string) ([]WUAInstallResult, error) {
+	session, err := NewUpdateSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating NewUpdateSession: %v", err)
+	}
+	defer session.Close()
+
+	updts, err := session.GetWUAUpdateCollection(ctx, updateIDQuery(updateIDs))
+	if err != nil {
+		return nil, fmt.Errorf("error calling GetWUAUpdateCollection: %v", err)
+	}
+	defer updts.Release()
+
+	return session.UninstallWUAUpdateCollectionAsync(ctx, updts, nil)
+}
+
 // GetWUAUpdateCollection queries the Windows Update Agent API searcher with the provided query
 // and returns a IUpdateCollection.
 func (s *IUpdateSession) GetWUAUpdateCollection(ctx context.Context, query string) (*IUpdateCollection, error) {
@@ -438,6 +863,12 @@ func (s *IUpdateSession) GetWUAUpdateCollection(ctx context.Context, query strin
 	searcher := searcherRaw.ToIDispatch()
 	defer searcher.Release()
 
+	return collectionFromSearcher(ctx, searcher, query)
+}
+
+// collectionFromSearcher runs query against an already-configured
+// IUpdateSearcher and returns the resulting IUpdateCollection.
+func collectionFromSearcher(ctx context.Context, searcher *ole.IDispatch, query string) (*IUpdateCollection, error) {
 	// returns ISearchResult
 	// https://msdn.microsoft.com/en-us/library/windows/desktop/aa386077(v=vs.85).aspx
 	resultRaw, err := searcher.CallMethod("Search", query)